@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/rancher/machine/libmachine/drivers"
@@ -16,42 +17,73 @@ import (
 	"github.com/rancher/machine/libmachine/state"
 )
 
+// additionalDisk describes an extra persistent disk to attach to the
+// instance, parsed from a repeated --google-additional-disk flag.
+type additionalDisk struct {
+	Name       string `json:"name"`
+	SizeGb     int    `json:"size"`
+	Type       string `json:"type"`
+	Mode       string `json:"mode"`
+	AutoDelete bool   `json:"autodelete"`
+}
+
+// localSSD describes a local SSD to attach to the instance, parsed from a
+// repeated --google-local-ssd flag.
+type localSSD struct {
+	Interface string `json:"interface"`
+}
+
 // Driver is a struct compatible with the docker.hosts.drivers.Driver interface.
 type Driver struct {
 	*drivers.BaseDriver
-	Auth                       string
-	Zone                       string
-	MachineType                string
-	MachineImage               string
-	DiskType                   string
-	Address                    string
-	Network                    string
-	Subnetwork                 string
-	Preemptible                bool
-	UseInternalIP              bool
-	UseInternalIPOnly          bool
-	Scopes                     string
-	DiskSize                   int
-	Project                    string
-	Tags                       string
-	Labels                     string
-	UseExisting                bool
-	OpenPorts                  []string
-	ExternalFirewallRulePrefix string
-	InternalFirewallRulePrefix string
-	Userdata                   string
+	Auth                        string
+	Zone                        string
+	MachineType                 string
+	MachineImage                string
+	DiskType                    string
+	Address                     string
+	Network                     string
+	Subnetwork                  string
+	Preemptible                 bool
+	UseInternalIP               bool
+	UseInternalIPOnly           bool
+	Scopes                      string
+	DiskSize                    int
+	Project                     string
+	Tags                        string
+	Labels                      string
+	UseExisting                 bool
+	OpenPorts                   []string
+	ExternalFirewallRulePrefix  string
+	InternalFirewallRulePrefix  string
+	Userdata                    string
+	AuthVaultAddr               string
+	AuthVaultToken              string
+	AuthVaultPath               string
+	OperationTimeout            int
+	ShieldedSecureBoot          bool
+	ShieldedVtpm                bool
+	ShieldedIntegrityMonitoring bool
+	ConfidentialCompute         bool
+	ServiceAccountEmail         string
+	MinCpuPlatform              string
+	AdditionalDisks             []additionalDisk
+	LocalSSDs                   []localSSD
+	AcceleratorType             string
+	AcceleratorCount            int
 }
 
 const (
-	defaultZone        = "us-central1-a"
-	defaultUser        = "docker-user"
-	defaultMachineType = "n1-standard-1"
-	defaultImageName   = "ubuntu-os-cloud/global/images/ubuntu-2204-jammy-v20220420"
-	defaultScopes      = "https://www.googleapis.com/auth/devstorage.read_only,https://www.googleapis.com/auth/logging.write,https://www.googleapis.com/auth/monitoring.write"
-	defaultDiskType    = "pd-standard"
-	defaultDiskSize    = 10
-	defaultNetwork     = "default"
-	defaultSubnetwork  = ""
+	defaultZone             = "us-central1-a"
+	defaultUser             = "docker-user"
+	defaultMachineType      = "n1-standard-1"
+	defaultImageName        = "ubuntu-os-cloud/global/images/ubuntu-2204-jammy-v20220420"
+	defaultScopes           = "https://www.googleapis.com/auth/devstorage.read_only,https://www.googleapis.com/auth/logging.write,https://www.googleapis.com/auth/monitoring.write"
+	defaultDiskType         = "pd-standard"
+	defaultDiskSize         = 10
+	defaultNetwork          = "default"
+	defaultSubnetwork       = ""
+	defaultOperationTimeout = 300
 )
 
 // GetCreateFlags registers the flags this driver adds to
@@ -87,6 +119,21 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage:  "Base64 encoded GCE auth json",
 			EnvVar: "GOOGLE_AUTH_ENCODED_JSON",
 		},
+		mcnflag.StringFlag{
+			Name:   "google-auth-vault-addr",
+			Usage:  "Address of the Vault server to source GCE auth tokens from",
+			EnvVar: "GOOGLE_AUTH_VAULT_ADDR",
+		},
+		mcnflag.StringFlag{
+			Name:   "google-auth-vault-token",
+			Usage:  "Vault token used to authenticate against the GCP secrets engine",
+			EnvVar: "GOOGLE_AUTH_VAULT_TOKEN",
+		},
+		mcnflag.StringFlag{
+			Name:   "google-auth-vault-path",
+			Usage:  "Vault path of the GCP secrets engine roleset/static account to request a token from",
+			EnvVar: "GOOGLE_AUTH_VAULT_PATH",
+		},
 		mcnflag.StringFlag{
 			Name:   "google-project",
 			Usage:  "GCE Project",
@@ -180,20 +227,158 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			EnvVar: "GOOGLE_VM_LABELS",
 			Value:  "",
 		},
+		mcnflag.IntFlag{
+			Name:   "google-operation-timeout",
+			Usage:  "GCE operation polling timeout in seconds, for instance/disk/firewall create, start, stop and delete",
+			Value:  defaultOperationTimeout,
+			EnvVar: "GOOGLE_OPERATION_TIMEOUT",
+		},
+		mcnflag.BoolFlag{
+			Name:   "google-shielded-secure-boot",
+			Usage:  "Enable Secure Boot on the created Shielded VM instance",
+			EnvVar: "GOOGLE_SHIELDED_SECURE_BOOT",
+		},
+		mcnflag.BoolFlag{
+			Name:   "google-shielded-vtpm",
+			Usage:  "Enable the virtual Trusted Platform Module on the created Shielded VM instance",
+			Value:  true,
+			EnvVar: "GOOGLE_SHIELDED_VTPM",
+		},
+		mcnflag.BoolFlag{
+			Name:   "google-shielded-integrity-monitoring",
+			Usage:  "Enable integrity monitoring on the created Shielded VM instance",
+			Value:  true,
+			EnvVar: "GOOGLE_SHIELDED_INTEGRITY_MONITORING",
+		},
+		mcnflag.BoolFlag{
+			Name:   "google-confidential-compute",
+			Usage:  "Enable Confidential Computing; requires an N2D or C2D machine type",
+			EnvVar: "GOOGLE_CONFIDENTIAL_COMPUTE",
+		},
+		mcnflag.StringFlag{
+			Name:   "google-service-account-email",
+			Usage:  "GCE Service Account Email to attach to the instance, instead of the default compute service account",
+			EnvVar: "GOOGLE_SERVICE_ACCOUNT_EMAIL",
+		},
+		mcnflag.StringFlag{
+			Name:   "google-min-cpu-platform",
+			Usage:  "GCE Minimum CPU Platform for the created instance",
+			EnvVar: "GOOGLE_MIN_CPU_PLATFORM",
+		},
+		mcnflag.StringSliceFlag{
+			Name:   "google-additional-disk",
+			Usage:  "Additional persistent disk to attach, e.g. name=foo,size=100,type=pd-ssd,mode=READ_WRITE,autodelete=true (may be specified multiple times)",
+			EnvVar: "GOOGLE_ADDITIONAL_DISK",
+		},
+		mcnflag.StringSliceFlag{
+			Name:   "google-local-ssd",
+			Usage:  "Attach a local SSD, e.g. interface=NVME (may be specified multiple times)",
+			EnvVar: "GOOGLE_LOCAL_SSD",
+		},
+		mcnflag.StringFlag{
+			Name:   "google-accelerator-type",
+			Usage:  "GCE Accelerator (GPU/TPU) type to attach, e.g. nvidia-tesla-t4",
+			EnvVar: "GOOGLE_ACCELERATOR_TYPE",
+		},
+		mcnflag.IntFlag{
+			Name:   "google-accelerator-count",
+			Usage:  "Number of accelerators of --google-accelerator-type to attach",
+			EnvVar: "GOOGLE_ACCELERATOR_COUNT",
+		},
 	}
 }
 
+// parseAdditionalDisk parses a single --google-additional-disk value of the
+// form name=foo,size=100,type=pd-ssd,mode=READ_WRITE,autodelete=true.
+func parseAdditionalDisk(spec string) (additionalDisk, error) {
+	disk := additionalDisk{
+		Type:       defaultDiskType,
+		Mode:       "READ_WRITE",
+		AutoDelete: true,
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return additionalDisk{}, fmt.Errorf("invalid --google-additional-disk entry %q: expected key=value", pair)
+		}
+		switch key {
+		case "name":
+			disk.Name = value
+		case "size":
+			size, err := strconv.Atoi(value)
+			if err != nil {
+				return additionalDisk{}, fmt.Errorf("invalid size %q in --google-additional-disk: %w", value, err)
+			}
+			disk.SizeGb = size
+		case "type":
+			disk.Type = value
+		case "mode":
+			if value != "READ_WRITE" && value != "READ_ONLY" {
+				return additionalDisk{}, fmt.Errorf("invalid mode %q in --google-additional-disk: must be READ_WRITE or READ_ONLY", value)
+			}
+			disk.Mode = value
+		case "autodelete":
+			autoDelete, err := strconv.ParseBool(value)
+			if err != nil {
+				return additionalDisk{}, fmt.Errorf("invalid autodelete %q in --google-additional-disk: %w", value, err)
+			}
+			disk.AutoDelete = autoDelete
+		default:
+			return additionalDisk{}, fmt.Errorf("unknown --google-additional-disk key %q", key)
+		}
+	}
+
+	if disk.Name == "" {
+		return additionalDisk{}, errors.New("--google-additional-disk requires a name")
+	}
+	if disk.SizeGb <= 0 {
+		return additionalDisk{}, fmt.Errorf("--google-additional-disk %q requires size > 0", disk.Name)
+	}
+
+	return disk, nil
+}
+
+// parseLocalSSD parses a single --google-local-ssd value of the form
+// interface=NVME.
+func parseLocalSSD(spec string) (localSSD, error) {
+	ssd := localSSD{Interface: "SCSI"}
+
+	if spec == "" {
+		return ssd, nil
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return localSSD{}, fmt.Errorf("invalid --google-local-ssd entry %q: expected key=value", pair)
+		}
+		if key != "interface" {
+			return localSSD{}, fmt.Errorf("unknown --google-local-ssd key %q", key)
+		}
+		if value != "NVME" && value != "SCSI" {
+			return localSSD{}, fmt.Errorf("invalid --google-local-ssd interface %q: must be NVME or SCSI", value)
+		}
+		ssd.Interface = value
+	}
+
+	return ssd, nil
+}
+
 // NewDriver creates a Driver with the specified storePath.
 func NewDriver(machineName string, storePath string) *Driver {
 	return &Driver{
-		Zone:         defaultZone,
-		DiskType:     defaultDiskType,
-		DiskSize:     defaultDiskSize,
-		MachineType:  defaultMachineType,
-		MachineImage: defaultImageName,
-		Network:      defaultNetwork,
-		Subnetwork:   defaultSubnetwork,
-		Scopes:       defaultScopes,
+		Zone:                        defaultZone,
+		DiskType:                    defaultDiskType,
+		DiskSize:                    defaultDiskSize,
+		MachineType:                 defaultMachineType,
+		MachineImage:                defaultImageName,
+		Network:                     defaultNetwork,
+		Subnetwork:                  defaultSubnetwork,
+		Scopes:                      defaultScopes,
+		OperationTimeout:            defaultOperationTimeout,
+		ShieldedVtpm:                true,
+		ShieldedIntegrityMonitoring: true,
 		BaseDriver: &drivers.BaseDriver{
 			SSHUser:     defaultUser,
 			MachineName: machineName,
@@ -243,6 +428,9 @@ func (d *Driver) UnmarshalJSON(data []byte) error {
 	if _, ok := driverOpts.Values["google-auth-encoded-json"]; ok {
 		d.Auth = driverOpts.String("google-auth-encoded-json")
 	}
+	if _, ok := driverOpts.Values["google-auth-vault-token"]; ok {
+		d.AuthVaultToken = driverOpts.String("google-auth-vault-token")
+	}
 
 	return nil
 }
@@ -254,6 +442,9 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 		return errors.New("no Google Cloud Project name specified (--google-project)")
 	}
 	d.Auth = flags.String("google-auth-encoded-json")
+	d.AuthVaultAddr = flags.String("google-auth-vault-addr")
+	d.AuthVaultToken = flags.String("google-auth-vault-token")
+	d.AuthVaultPath = flags.String("google-auth-vault-path")
 
 	d.Zone = flags.String("google-zone")
 	d.UseExisting = flags.Bool("google-use-existing")
@@ -275,15 +466,60 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 		d.ExternalFirewallRulePrefix = flags.String("google-external-firewall-rule-prefix")
 		d.InternalFirewallRulePrefix = flags.String("google-internal-firewall-rule-prefix")
 		d.Labels = flags.String("google-vm-labels")
+		d.ShieldedSecureBoot = flags.Bool("google-shielded-secure-boot")
+		d.ShieldedVtpm = flags.Bool("google-shielded-vtpm")
+		d.ShieldedIntegrityMonitoring = flags.Bool("google-shielded-integrity-monitoring")
+		d.ConfidentialCompute = flags.Bool("google-confidential-compute")
+		d.ServiceAccountEmail = flags.String("google-service-account-email")
+		d.MinCpuPlatform = flags.String("google-min-cpu-platform")
+
+		if d.ConfidentialCompute && !isConfidentialComputeMachineType(d.MachineType) {
+			return fmt.Errorf("--google-confidential-compute requires an N2D or C2D machine type, got %q", d.MachineType)
+		}
+
+		d.AdditionalDisks = nil
+		for _, spec := range flags.StringSlice("google-additional-disk") {
+			disk, err := parseAdditionalDisk(spec)
+			if err != nil {
+				return err
+			}
+			d.AdditionalDisks = append(d.AdditionalDisks, disk)
+		}
+
+		d.LocalSSDs = nil
+		for _, spec := range flags.StringSlice("google-local-ssd") {
+			ssd, err := parseLocalSSD(spec)
+			if err != nil {
+				return err
+			}
+			d.LocalSSDs = append(d.LocalSSDs, ssd)
+		}
+
+		d.AcceleratorType = flags.String("google-accelerator-type")
+		d.AcceleratorCount = flags.Int("google-accelerator-count")
+		if d.AcceleratorType != "" && d.AcceleratorCount == 0 {
+			d.AcceleratorCount = 1
+		}
 	}
 	d.SSHUser = flags.String("google-username")
 	d.SSHPort = 22
 	d.Userdata = flags.String("google-userdata")
+	d.OperationTimeout = flags.Int("google-operation-timeout")
 	d.SetSwarmConfigFromFlags(flags)
 
 	return nil
 }
 
+// isConfidentialComputeMachineType reports whether the given machine type
+// family supports Confidential Computing.
+func isConfidentialComputeMachineType(machineType string) bool {
+	family := machineType
+	if idx := strings.Index(machineType, "-"); idx != -1 {
+		family = machineType[:idx]
+	}
+	return family == "n2d" || family == "c2d"
+}
+
 // PreCreateCheck is called to enforce pre-creation steps
 func (d *Driver) PreCreateCheck() error {
 	c, err := newComputeUtil(d)
@@ -322,6 +558,18 @@ func (d *Driver) PreCreateCheck() error {
 		d.Userdata = string(file)
 	}
 
+	if d.AcceleratorType != "" {
+		if d.AcceleratorCount <= 0 {
+			return fmt.Errorf("--google-accelerator-count must be > 0 when --google-accelerator-type is set, got %d", d.AcceleratorCount)
+		}
+
+		log.Infof("Check that zone %q offers accelerator %q", d.Zone, d.AcceleratorType)
+
+		if _, err := c.service.AcceleratorTypes.Get(d.Project, d.Zone, d.AcceleratorType).Do(); err != nil {
+			return fmt.Errorf("accelerator type %q is not available in zone %q: %v", d.AcceleratorType, d.Zone, err)
+		}
+	}
+
 	return nil
 }
 
@@ -507,6 +755,22 @@ func (d *Driver) Remove() error {
 	// later. If we fail to destroy one firewall,
 	// we should still attempt to remove the other.
 	var errs []error
+
+	for _, disk := range d.AdditionalDisks {
+		if disk.AutoDelete {
+			// GCE already tore this down along with the instance.
+			continue
+		}
+		if err := c.deleteNamedDisk(disk.Name); err != nil {
+			if isNotFound(err) {
+				log.Infof("additional disk '%s' does not exist, nothing to do", disk.Name)
+			} else {
+				log.Warnf("failed to remove additional disk '%s': %v", disk.Name, err)
+				errs = append(errs, err)
+			}
+		}
+	}
+
 	if len(d.OpenPorts) > 0 {
 		externalFwRule, err := c.externalFirewallRule()
 		if isNotFound(err) {
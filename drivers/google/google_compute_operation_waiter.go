@@ -0,0 +1,134 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	compute "google.golang.org/api/compute/v1"
+
+	"github.com/rancher/machine/libmachine/log"
+)
+
+// computeOperationWaiterType identifies which Operations API a
+// ComputeOperationWaiter should poll, since GCE exposes separate endpoints
+// for global and zone scoped operations. A region-scoped variant can be
+// added here once a caller needs to wait on a region operation.
+type computeOperationWaiterType int
+
+const (
+	computeOperationWaiterTypeGlobal computeOperationWaiterType = iota
+	computeOperationWaiterTypeZone
+)
+
+const (
+	operationPollInitialInterval = 1 * time.Second
+	operationPollMaxInterval     = 15 * time.Second
+)
+
+// ComputeOperationWaiter polls a long-running compute.Operation to
+// completion, replacing the ad-hoc Do()-then-sleep loops previously
+// scattered across computeUtil.
+type ComputeOperationWaiter struct {
+	Service *compute.Service
+	Op      *compute.Operation
+	Project string
+	Type    computeOperationWaiterType
+	Zone    string
+}
+
+// Wait polls the operation on an exponential backoff with jitter until it
+// reaches status DONE, the context is cancelled, or GCE reports an error.
+func (w *ComputeOperationWaiter) Wait(ctx context.Context) error {
+	op := w.Op
+	interval := operationPollInitialInterval
+
+	for {
+		if op.Status == "DONE" {
+			return w.operationError(op)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for operation %q: %w", op.Name, ctx.Err())
+		case <-time.After(interval):
+		}
+
+		var err error
+		op, err = w.get()
+		if err != nil {
+			return fmt.Errorf("error polling operation %q: %w", w.Op.Name, err)
+		}
+
+		log.Debugf("operation %q is %q", op.Name, op.Status)
+
+		interval = nextInterval(interval)
+	}
+}
+
+func (w *ComputeOperationWaiter) get() (*compute.Operation, error) {
+	switch w.Type {
+	case computeOperationWaiterTypeGlobal:
+		return w.Service.GlobalOperations.Get(w.Project, w.Op.Name).Do()
+	default:
+		return w.Service.ZoneOperations.Get(w.Project, w.Zone, w.Op.Name).Do()
+	}
+}
+
+func (w *ComputeOperationWaiter) operationError(op *compute.Operation) error {
+	if op.Error == nil || len(op.Error.Errors) == 0 {
+		return nil
+	}
+
+	messages := make([]string, 0, len(op.Error.Errors))
+	for _, e := range op.Error.Errors {
+		messages = append(messages, fmt.Sprintf("%s: %s", e.Code, e.Message))
+	}
+
+	return fmt.Errorf("operation %q failed: %s", op.Name, strings.Join(messages, "; "))
+}
+
+// nextInterval doubles the poll interval up to operationPollMaxInterval and
+// adds up to 50% jitter so that concurrent waiters don't all poll in lockstep.
+func nextInterval(interval time.Duration) time.Duration {
+	next := interval * 2
+	if next > operationPollMaxInterval {
+		next = operationPollMaxInterval
+	}
+	jitter := time.Duration(rand.Int63n(int64(next) / 2))
+	return next + jitter
+}
+
+// waitForOp waits for a zonal operation to complete using the computeUtil's
+// configured operation timeout.
+func (c *computeUtil) waitForOp(op *compute.Operation) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.operationTimeout)
+	defer cancel()
+
+	waiter := &ComputeOperationWaiter{
+		Service: c.service,
+		Op:      op,
+		Project: c.project,
+		Type:    computeOperationWaiterTypeZone,
+		Zone:    c.zone,
+	}
+	return waiter.Wait(ctx)
+}
+
+// waitForGlobalOp waits for a global operation, such as a firewall rule
+// create or delete, to complete using the computeUtil's configured
+// operation timeout.
+func (c *computeUtil) waitForGlobalOp(op *compute.Operation) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.operationTimeout)
+	defer cancel()
+
+	waiter := &ComputeOperationWaiter{
+		Service: c.service,
+		Op:      op,
+		Project: c.project,
+		Type:    computeOperationWaiterTypeGlobal,
+	}
+	return waiter.Wait(ctx)
+}
@@ -0,0 +1,103 @@
+package google
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	compute "google.golang.org/api/compute/v1"
+)
+
+func TestComputeOperationWaiterOperationError(t *testing.T) {
+	w := &ComputeOperationWaiter{}
+
+	cases := []struct {
+		name     string
+		op       *compute.Operation
+		wantErr  bool
+		wantMsgs []string
+	}{
+		{
+			name: "no error",
+			op:   &compute.Operation{Name: "op-1"},
+		},
+		{
+			name: "empty errors slice",
+			op:   &compute.Operation{Name: "op-2", Error: &compute.OperationError{}},
+		},
+		{
+			name: "single error",
+			op: &compute.Operation{
+				Name: "op-3",
+				Error: &compute.OperationError{
+					Errors: []*compute.OperationErrorErrors{
+						{Code: "RESOURCE_ERROR", Message: "quota exceeded"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "multiple errors are aggregated",
+			op: &compute.Operation{
+				Name: "op-4",
+				Error: &compute.OperationError{
+					Errors: []*compute.OperationErrorErrors{
+						{Code: "ERR_A", Message: "first"},
+						{Code: "ERR_B", Message: "second"},
+					},
+				},
+			},
+			wantErr:  true,
+			wantMsgs: []string{"ERR_A: first", "ERR_B: second"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := w.operationError(c.op)
+			if c.wantErr && err == nil {
+				t.Fatalf("operationError(%+v) = nil; want error", c.op)
+			}
+			if !c.wantErr && err != nil {
+				t.Fatalf("operationError(%+v) = %v; want nil", c.op, err)
+			}
+			for _, m := range c.wantMsgs {
+				if !strings.Contains(err.Error(), m) {
+					t.Fatalf("operationError(%+v) = %v; want it to contain %q", c.op, err, m)
+				}
+			}
+		})
+	}
+}
+
+func TestNextInterval(t *testing.T) {
+	cases := []struct {
+		name string
+		in   time.Duration
+	}{
+		{"doubles below the cap", 1 * time.Second},
+		{"doubles again", 4 * time.Second},
+		{"clamps at the cap", operationPollMaxInterval},
+		{"clamps when doubling would exceed the cap", operationPollMaxInterval - 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := nextInterval(c.in)
+			if got < c.in {
+				t.Fatalf("nextInterval(%v) = %v; want >= input", c.in, got)
+			}
+			if got > operationPollMaxInterval+operationPollMaxInterval/2 {
+				t.Fatalf("nextInterval(%v) = %v; exceeds max interval plus jitter", c.in, got)
+			}
+			base := c.in * 2
+			if base > operationPollMaxInterval {
+				base = operationPollMaxInterval
+			}
+			if got < base || got >= base+base/2 {
+				t.Fatalf("nextInterval(%v) = %v; want in [%v, %v)", c.in, got, base, base+base/2)
+			}
+		})
+	}
+}
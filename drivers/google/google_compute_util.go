@@ -0,0 +1,522 @@
+package google
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	compute "google.golang.org/api/compute/v1"
+
+	"github.com/rancher/machine/libmachine/log"
+)
+
+// DriverScopes are the OAuth scopes requested when building a credentials-backed
+// http.Client for the compute API.
+var DriverScopes = []string{
+	"https://www.googleapis.com/auth/compute",
+	"https://www.googleapis.com/auth/devstorage.full_control",
+}
+
+const apiURL = "https://www.googleapis.com/compute/v1/projects/"
+
+const (
+	externalFirewallRuleLabelKey = "rancher-machine-external-firewall-rule"
+	internalFirewallRuleLabelKey = "rancher-machine-internal-firewall-rule"
+)
+
+// computeUtil is used to wrap the GCE compute API code, allowing for remote
+// tests to be done without leaning on a GCE project directly.
+type computeUtil struct {
+	zone                       string
+	instanceName               string
+	userName                   string
+	project                    string
+	service                    *compute.Service
+	zoneURL                    string
+	globalURL                  string
+	ipAddress                  string
+	externalFirewallRulePrefix string
+	internalFirewallRulePrefix string
+	openPorts                  []string
+	operationTimeout           time.Duration
+}
+
+// vaultTokenResponse models the subset of a Vault GCP secrets engine token
+// response that the driver cares about.
+type vaultTokenResponse struct {
+	Data struct {
+		Token    string `json:"token"`
+		TokenTTL int    `json:"token_ttl"`
+	} `json:"data"`
+}
+
+// vaultRequestTimeout bounds how long a single Vault token request is allowed
+// to block, so a hung or unreachable Vault server can't stall auth
+// resolution (and every operation that depends on it) indefinitely.
+const vaultRequestTimeout = 30 * time.Second
+
+// vaultTokenSource is an oauth2.TokenSource that fetches short-lived OAuth
+// tokens from a HashiCorp Vault GCP secrets engine roleset/static-account path.
+type vaultTokenSource struct {
+	addr  string
+	token string
+	path  string
+}
+
+func (v *vaultTokenSource) Token() (*oauth2.Token, error) {
+	url := strings.TrimRight(v.addr, "/") + "/v1/" + strings.TrimLeft(v.path, "/")
+
+	ctx, cancel := context.WithTimeout(context.Background(), vaultRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error building vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+
+	client := &http.Client{Timeout: vaultRequestTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error calling vault at %q: %w", v.path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned status %d for path %q", resp.StatusCode, v.path)
+	}
+
+	var tokenResp vaultTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("error decoding vault response: %w", err)
+	}
+	if tokenResp.Data.Token == "" {
+		return nil, fmt.Errorf("vault response at %q did not contain a token", v.path)
+	}
+
+	expiry := time.Now().Add(time.Hour)
+	if tokenResp.Data.TokenTTL > 0 {
+		expiry = time.Now().Add(time.Duration(tokenResp.Data.TokenTTL) * time.Second)
+	}
+
+	return &oauth2.Token{
+		AccessToken: tokenResp.Data.Token,
+		TokenType:   "Bearer",
+		Expiry:      expiry,
+	}, nil
+}
+
+// newVaultTokenSource wraps a vaultTokenSource in an oauth2.ReuseTokenSource
+// so tokens are cached in memory until they near expiry.
+func newVaultTokenSource(addr, token, path string) oauth2.TokenSource {
+	return oauth2.ReuseTokenSource(nil, &vaultTokenSource{addr: addr, token: token, path: path})
+}
+
+// newComputeClient resolves an *http.Client authorized against the compute
+// API, preferring Vault-issued tokens when configured, then the base64-encoded
+// service account JSON key, and otherwise Application Default Credentials.
+func newComputeClient(d *Driver) (*http.Client, error) {
+	if d.AuthVaultAddr != "" && d.AuthVaultToken != "" && d.AuthVaultPath != "" {
+		log.Infof("Using Vault at %q to source GCE credentials", d.AuthVaultAddr)
+		tokenSource := newVaultTokenSource(d.AuthVaultAddr, d.AuthVaultToken, d.AuthVaultPath)
+		return oauth2.NewClient(oauth2.NoContext, tokenSource), nil
+	}
+
+	if d.Auth != "" {
+		log.Infof("Using credentials from --google-auth-encoded-json")
+
+		decoded, err := base64.StdEncoding.DecodeString(d.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding auth json: %w", err)
+		}
+
+		config, err := google.JWTConfigFromJSON(decoded, DriverScopes...)
+		if err != nil {
+			return nil, fmt.Errorf("error building JWT config from auth json: %w", err)
+		}
+
+		return config.Client(oauth2.NoContext), nil
+	}
+
+	// No explicit credentials were given: fall through to Application Default
+	// Credentials, which in order tries GOOGLE_APPLICATION_CREDENTIALS, the
+	// gcloud user credentials in the well-known config dir, and finally the
+	// GCE metadata server when running on a GCE instance.
+	ctx := context.Background()
+	creds, err := google.FindDefaultCredentials(ctx, DriverScopes...)
+	if err != nil {
+		return nil, fmt.Errorf("error finding application default credentials: %w", err)
+	}
+	log.Infof("Using application default credentials (source: %s)", defaultCredentialsSource(creds))
+
+	return oauth2.NewClient(ctx, creds.TokenSource), nil
+}
+
+// defaultCredentialsSource describes, for logging purposes, which leg of the
+// Application Default Credentials chain was used to authenticate.
+func defaultCredentialsSource(creds *google.Credentials) string {
+	if len(creds.JSON) == 0 {
+		return "GCE metadata server"
+	}
+	if os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != "" {
+		return "GOOGLE_APPLICATION_CREDENTIALS"
+	}
+	return "gcloud user credentials"
+}
+
+// newComputeUtil creates and initializes a computeUtil, resolving credentials
+// from the driver's configured auth options.
+func newComputeUtil(d *Driver) (*computeUtil, error) {
+	client, err := newComputeClient(d)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := compute.New(client)
+	if err != nil {
+		return nil, fmt.Errorf("error building compute service: %w", err)
+	}
+
+	c := &computeUtil{
+		zone:                       d.Zone,
+		instanceName:               d.MachineName,
+		userName:                   d.SSHUser,
+		project:                    d.Project,
+		service:                    service,
+		zoneURL:                    apiURL + d.Project + "/zones/" + d.Zone,
+		globalURL:                  apiURL + d.Project + "/global",
+		externalFirewallRulePrefix: d.ExternalFirewallRulePrefix,
+		internalFirewallRulePrefix: d.InternalFirewallRulePrefix,
+		openPorts:                  d.OpenPorts,
+		operationTimeout:           time.Duration(d.OperationTimeout) * time.Second,
+	}
+
+	return c, nil
+}
+
+func isNotFound(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "not found")
+}
+
+func (c *computeUtil) diskName() string {
+	return c.instanceName + "-disk"
+}
+
+func (c *computeUtil) instance() (*compute.Instance, error) {
+	return c.service.Instances.Get(c.project, c.zone, c.instanceName).Do()
+}
+
+func (c *computeUtil) disk() (*compute.Disk, error) {
+	return c.service.Disks.Get(c.project, c.zone, c.diskName()).Do()
+}
+
+func (c *computeUtil) ip() (string, error) {
+	if c.ipAddress != "" {
+		return c.ipAddress, nil
+	}
+
+	instance, err := c.instance()
+	if err != nil {
+		return "", err
+	}
+
+	for _, networkInterface := range instance.NetworkInterfaces {
+		for _, accessConfig := range networkInterface.AccessConfigs {
+			if accessConfig.NatIP != "" {
+				c.ipAddress = accessConfig.NatIP
+				return c.ipAddress, nil
+			}
+		}
+		if networkInterface.NetworkIP != "" {
+			c.ipAddress = networkInterface.NetworkIP
+		}
+	}
+
+	return c.ipAddress, nil
+}
+
+func (c *computeUtil) firewallRule(name string, ports []string, network, sourceRange string) *compute.Firewall {
+	var allowed []*compute.FirewallAllowed
+	byProtocol := map[string][]string{}
+	for _, p := range ports {
+		parts := strings.SplitN(p, "/", 2)
+		protocol := "tcp"
+		if len(parts) == 2 {
+			protocol = parts[1]
+		}
+		byProtocol[protocol] = append(byProtocol[protocol], parts[0])
+	}
+	for protocol, portList := range byProtocol {
+		allowed = append(allowed, &compute.FirewallAllowed{
+			IPProtocol: protocol,
+			Ports:      portList,
+		})
+	}
+
+	return &compute.Firewall{
+		Name:         name,
+		Network:      network,
+		Allowed:      allowed,
+		SourceRanges: []string{sourceRange},
+	}
+}
+
+func (c *computeUtil) externalFirewallRuleName() string {
+	return c.externalFirewallRulePrefix + "-" + c.instanceName
+}
+
+func (c *computeUtil) internalFirewallRuleName() string {
+	return c.internalFirewallRulePrefix + "-" + c.instanceName
+}
+
+func (c *computeUtil) externalFirewallRule() (*compute.Firewall, error) {
+	return c.service.Firewalls.Get(c.project, c.externalFirewallRuleName()).Do()
+}
+
+func (c *computeUtil) internalFirewallRule() (*compute.Firewall, error) {
+	return c.service.Firewalls.Get(c.project, c.internalFirewallRuleName()).Do()
+}
+
+func (c *computeUtil) openPublicFirewallPorts(d *Driver) error {
+	rule := c.firewallRule(c.externalFirewallRuleName(), d.OpenPorts, c.globalURL+"/networks/"+d.Network, "0.0.0.0/0")
+	op, err := c.service.Firewalls.Insert(c.project, rule).Do()
+	if err != nil {
+		return err
+	}
+	return c.waitForGlobalOp(op)
+}
+
+func (c *computeUtil) openInternalFirewallPorts(d *Driver) error {
+	rule := c.firewallRule(c.internalFirewallRuleName(), d.OpenPorts, c.globalURL+"/networks/"+d.Network, "10.0.0.0/8")
+	op, err := c.service.Firewalls.Insert(c.project, rule).Do()
+	if err != nil {
+		return err
+	}
+	return c.waitForGlobalOp(op)
+}
+
+func (c *computeUtil) CleanUpFirewallRule(rule *compute.Firewall, labelKey string) error {
+	op, err := c.service.Firewalls.Delete(c.project, rule.Name).Do()
+	if err != nil {
+		return err
+	}
+	return c.waitForGlobalOp(op)
+}
+
+func (c *computeUtil) metadata(d *Driver) (*compute.Metadata, error) {
+	sshKey, err := os.ReadFile(d.GetSSHKeyPath() + ".pub")
+	if err != nil {
+		return nil, fmt.Errorf("error reading public SSH key: %w", err)
+	}
+
+	items := []*compute.MetadataItems{
+		{
+			Key:   "sshKeys",
+			Value: strPtr(fmt.Sprintf("%s:%s", d.SSHUser, string(sshKey))),
+		},
+	}
+	if d.Userdata != "" {
+		items = append(items, &compute.MetadataItems{
+			Key:   "user-data",
+			Value: strPtr(d.Userdata),
+		})
+	}
+
+	return &compute.Metadata{Items: items}, nil
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func (c *computeUtil) createInstance(d *Driver) error {
+	log.Infof("Creating instance")
+
+	metadata, err := c.metadata(d)
+	if err != nil {
+		return err
+	}
+
+	var labels map[string]string
+	if d.Labels != "" {
+		labels = map[string]string{}
+		for _, kv := range strings.Split(d.Labels, ",") {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) == 2 {
+				labels[parts[0]] = parts[1]
+			}
+		}
+	}
+
+	instance := &compute.Instance{
+		Name:        c.instanceName,
+		Description: "Rancher-machine generated host",
+		MachineType: c.zoneURL + "/machineTypes/" + d.MachineType,
+		Disks: []*compute.AttachedDisk{
+			{
+				Boot:       true,
+				AutoDelete: true,
+				InitializeParams: &compute.AttachedDiskInitializeParams{
+					DiskName:    c.diskName(),
+					SourceImage: apiURL + d.MachineImage,
+					DiskSizeGb:  int64(d.DiskSize),
+					DiskType:    c.zoneURL + "/diskTypes/" + d.DiskType,
+				},
+			},
+		},
+		NetworkInterfaces: []*compute.NetworkInterface{
+			{
+				Network:    c.globalURL + "/networks/" + d.Network,
+				Subnetwork: d.Subnetwork,
+			},
+		},
+		Tags: &compute.Tags{
+			Items: strings.Split(d.Tags, ","),
+		},
+		Metadata: metadata,
+		Labels:   labels,
+		Scheduling: &compute.Scheduling{
+			Preemptible: d.Preemptible,
+		},
+		ServiceAccounts: []*compute.ServiceAccount{
+			{
+				Email:  "default",
+				Scopes: strings.Split(d.Scopes, ","),
+			},
+		},
+		ShieldedInstanceConfig: &compute.ShieldedInstanceConfig{
+			EnableSecureBoot:          d.ShieldedSecureBoot,
+			EnableVtpm:                d.ShieldedVtpm,
+			EnableIntegrityMonitoring: d.ShieldedIntegrityMonitoring,
+		},
+		MinCpuPlatform: d.MinCpuPlatform,
+	}
+
+	for _, disk := range d.AdditionalDisks {
+		instance.Disks = append(instance.Disks, &compute.AttachedDisk{
+			AutoDelete: disk.AutoDelete,
+			Mode:       disk.Mode,
+			InitializeParams: &compute.AttachedDiskInitializeParams{
+				DiskName:   disk.Name,
+				DiskSizeGb: int64(disk.SizeGb),
+				DiskType:   c.zoneURL + "/diskTypes/" + disk.Type,
+			},
+		})
+	}
+
+	for _, ssd := range d.LocalSSDs {
+		instance.Disks = append(instance.Disks, &compute.AttachedDisk{
+			Type:       "SCRATCH",
+			AutoDelete: true,
+			Interface:  ssd.Interface,
+			InitializeParams: &compute.AttachedDiskInitializeParams{
+				DiskType: c.zoneURL + "/diskTypes/local-ssd",
+			},
+		})
+	}
+
+	if d.ServiceAccountEmail != "" {
+		instance.ServiceAccounts[0].Email = d.ServiceAccountEmail
+	}
+
+	if d.ConfidentialCompute {
+		instance.ConfidentialInstanceConfig = &compute.ConfidentialInstanceConfig{
+			EnableConfidentialCompute: true,
+		}
+	}
+
+	if d.AcceleratorType != "" {
+		instance.GuestAccelerators = []*compute.AcceleratorConfig{
+			{
+				AcceleratorType:  c.zoneURL + "/acceleratorTypes/" + d.AcceleratorType,
+				AcceleratorCount: int64(d.AcceleratorCount),
+			},
+		}
+
+		// GCE does not support live migration for accelerated instances.
+		instance.Scheduling.OnHostMaintenance = "TERMINATE"
+		automaticRestart := !d.Preemptible
+		instance.Scheduling.AutomaticRestart = &automaticRestart
+	}
+
+	if !d.UseInternalIPOnly {
+		instance.NetworkInterfaces[0].AccessConfigs = []*compute.AccessConfig{
+			{
+				Type:  "ONE_TO_ONE_NAT",
+				NatIP: d.Address,
+			},
+		}
+	}
+
+	op, err := c.service.Instances.Insert(c.project, c.zone, instance).Do()
+	if err != nil {
+		return fmt.Errorf("error creating instance: %w", err)
+	}
+
+	return c.waitForOp(op)
+}
+
+func (c *computeUtil) configureInstance(d *Driver) error {
+	metadata, err := c.metadata(d)
+	if err != nil {
+		return err
+	}
+
+	instance, err := c.instance()
+	if err != nil {
+		return err
+	}
+
+	op, err := c.service.Instances.SetMetadata(c.project, c.zone, c.instanceName, &compute.Metadata{
+		Items:       metadata.Items,
+		Fingerprint: instance.Metadata.Fingerprint,
+	}).Do()
+	if err != nil {
+		return fmt.Errorf("error updating instance metadata: %w", err)
+	}
+
+	return c.waitForOp(op)
+}
+
+func (c *computeUtil) startInstance() error {
+	op, err := c.service.Instances.Start(c.project, c.zone, c.instanceName).Do()
+	if err != nil {
+		return err
+	}
+	return c.waitForOp(op)
+}
+
+func (c *computeUtil) stopInstance() error {
+	op, err := c.service.Instances.Stop(c.project, c.zone, c.instanceName).Do()
+	if err != nil {
+		return err
+	}
+	return c.waitForOp(op)
+}
+
+func (c *computeUtil) deleteInstance() error {
+	op, err := c.service.Instances.Delete(c.project, c.zone, c.instanceName).Do()
+	if err != nil {
+		return err
+	}
+	return c.waitForOp(op)
+}
+
+func (c *computeUtil) deleteDisk() error {
+	return c.deleteNamedDisk(c.diskName())
+}
+
+func (c *computeUtil) deleteNamedDisk(name string) error {
+	op, err := c.service.Disks.Delete(c.project, c.zone, name).Do()
+	if err != nil {
+		return err
+	}
+	return c.waitForOp(op)
+}
@@ -0,0 +1,136 @@
+package google
+
+import "testing"
+
+func TestParseAdditionalDisk(t *testing.T) {
+	cases := []struct {
+		name    string
+		spec    string
+		want    additionalDisk
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			spec: "name=data,size=100",
+			want: additionalDisk{Name: "data", SizeGb: 100, Type: defaultDiskType, Mode: "READ_WRITE", AutoDelete: true},
+		},
+		{
+			name: "all fields",
+			spec: "name=data,size=200,type=pd-ssd,mode=READ_ONLY,autodelete=false",
+			want: additionalDisk{Name: "data", SizeGb: 200, Type: "pd-ssd", Mode: "READ_ONLY", AutoDelete: false},
+		},
+		{
+			name:    "missing name",
+			spec:    "size=100",
+			wantErr: true,
+		},
+		{
+			name:    "missing size",
+			spec:    "name=data",
+			wantErr: true,
+		},
+		{
+			name:    "zero size",
+			spec:    "name=data,size=0",
+			wantErr: true,
+		},
+		{
+			name:    "invalid size",
+			spec:    "name=data,size=notanumber",
+			wantErr: true,
+		},
+		{
+			name:    "invalid mode",
+			spec:    "name=data,size=100,mode=ReadWrite",
+			wantErr: true,
+		},
+		{
+			name:    "invalid autodelete",
+			spec:    "name=data,size=100,autodelete=maybe",
+			wantErr: true,
+		},
+		{
+			name:    "unknown key",
+			spec:    "name=data,size=100,bogus=1",
+			wantErr: true,
+		},
+		{
+			name:    "malformed pair",
+			spec:    "name",
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseAdditionalDisk(c.spec)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("parseAdditionalDisk(%q) = %+v, nil; want error", c.spec, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAdditionalDisk(%q) returned unexpected error: %v", c.spec, err)
+			}
+			if got != c.want {
+				t.Fatalf("parseAdditionalDisk(%q) = %+v, want %+v", c.spec, got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseLocalSSD(t *testing.T) {
+	cases := []struct {
+		name    string
+		spec    string
+		want    localSSD
+		wantErr bool
+	}{
+		{name: "default interface", spec: "", want: localSSD{Interface: "SCSI"}},
+		{name: "nvme", spec: "interface=NVME", want: localSSD{Interface: "NVME"}},
+		{name: "scsi", spec: "interface=SCSI", want: localSSD{Interface: "SCSI"}},
+		{name: "invalid interface", spec: "interface=IDE", wantErr: true},
+		{name: "unknown key", spec: "bogus=1", wantErr: true},
+		{name: "malformed pair", spec: "interface", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseLocalSSD(c.spec)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("parseLocalSSD(%q) = %+v, nil; want error", c.spec, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseLocalSSD(%q) returned unexpected error: %v", c.spec, err)
+			}
+			if got != c.want {
+				t.Fatalf("parseLocalSSD(%q) = %+v, want %+v", c.spec, got, c.want)
+			}
+		})
+	}
+}
+
+func TestIsConfidentialComputeMachineType(t *testing.T) {
+	cases := []struct {
+		machineType string
+		want        bool
+	}{
+		{"n2d-standard-4", true},
+		{"c2d-highcpu-8", true},
+		{"n2d", true},
+		{"n1-standard-1", false},
+		{"n2-standard-4", false},
+		{"c2-standard-4", false},
+		{"", false},
+	}
+
+	for _, c := range cases {
+		if got := isConfidentialComputeMachineType(c.machineType); got != c.want {
+			t.Errorf("isConfidentialComputeMachineType(%q) = %v, want %v", c.machineType, got, c.want)
+		}
+	}
+}